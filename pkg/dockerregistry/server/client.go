@@ -0,0 +1,40 @@
+package server
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+// Interface is the subset of the OpenShift image client that the registry needs in order to resolve
+// image streams, fetch images, and record new tags. It exists so tests can substitute a fake client
+// without depending on the generated clientset.
+type Interface interface {
+	Images() ImageInterface
+	ImageStreamTags(namespace string) ImageStreamTagInterface
+	ImageStreamImportsNamespacer
+}
+
+// ImageInterface has methods to work with Image resources.
+type ImageInterface interface {
+	Get(name string, options metav1.GetOptions) (*imageapiv1.Image, error)
+}
+
+// ImageStreamTagInterface has methods to work with ImageStreamTag resources.
+type ImageStreamTagInterface interface {
+	Delete(name string, options *metav1.DeleteOptions) error
+}
+
+// ImageStreamImportsNamespacer has methods to work with ImageStreamImport resources in a given namespace.
+type ImageStreamImportsNamespacer interface {
+	ImageStreamImports(namespace string) ImageStreamImportInterface
+}
+
+// ImageStreamImportInterface has methods to work with ImageStreamImport resources.
+//
+// ImageStreamImport replaced the legacy ImageStreamMapping as the way a push lands a tag: it carries the
+// image stream's import policy and reference policy through to the resulting tag, the same way
+// `oc import-image` does, and it retires a dependency on the legacy image API group.
+type ImageStreamImportInterface interface {
+	Create(isi *imageapiv1.ImageStreamImport) (*imageapiv1.ImageStreamImport, error)
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+)
+
+// EventAction identifies the lifecycle transition an Event describes.
+type EventAction string
+
+const (
+	EventActionManifestPush   EventAction = "manifest_push"
+	EventActionManifestPull   EventAction = "manifest_pull"
+	EventActionManifestDelete EventAction = "manifest_delete"
+	EventActionTagCreate      EventAction = "tag_create"
+	EventActionTagDelete      EventAction = "tag_delete"
+)
+
+// Event describes a single manifest or tag lifecycle transition.
+type Event struct {
+	Action     EventAction `json:"action"`
+	MediaType  string      `json:"mediaType,omitempty"`
+	Digest     string      `json:"digest,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	Repository string      `json:"repository"`
+	Tag        string      `json:"tag,omitempty"`
+	Actor      string      `json:"actor,omitempty"`
+	RequestID  string      `json:"requestId,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// EventSink delivers a batch of events to wherever they are configured to go. Write is expected to be
+// called off the request's hot path; implementations that talk to the network should do so
+// asynchronously and log delivery failures rather than propagating them back to the caller.
+type EventSink interface {
+	Write(events ...Event) error
+}
+
+// noopEventSink discards every event it is given. It is the default EventSink when no sinks are
+// configured, so that callers never need to nil-check before writing an event.
+type noopEventSink struct{}
+
+func (noopEventSink) Write(events ...Event) error { return nil }
+
+// DefaultEventSink is the EventSink NewEventSinksFromConfig returns when the registry configuration does
+// not define any event listeners, and the one tagService reports to when it has no EventSink of its own.
+var DefaultEventSink EventSink = noopEventSink{}
+
+// WebhookConfig configures a single HTTP webhook EventSink, as read from the registry YAML
+// configuration's events/listeners section.
+type WebhookConfig struct {
+	// Endpoint is the URL events are POSTed to.
+	Endpoint string
+
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+
+	// Backoff is the delay between retries of a failed delivery.
+	Backoff time.Duration
+
+	// MediaTypes, if non-empty, restricts delivery to events whose MediaType is in this list.
+	MediaTypes []string
+}
+
+// EventListenerConfig is the YAML shape of a single entry in the registry configuration's
+// "openshift.events.listeners" section. NewEventSinksFromConfig turns a slice of these into the EventSink
+// tagService and the manifest service report events to.
+type EventListenerConfig struct {
+	Endpoint   string            `yaml:"endpoint"`
+	Headers    map[string]string `yaml:"headers"`
+	Backoff    time.Duration     `yaml:"backoff"`
+	MediaTypes []string          `yaml:"mediatypes"`
+}
+
+// NewEventSinksFromConfig builds the EventSink the registry configuration loader should hand to
+// tagService and the manifest service: one webhookEventSink per configured listener, fanned out behind a
+// single EventSink. It returns DefaultEventSink when listeners is empty, so callers don't need to special
+// case an unconfigured events section.
+func NewEventSinksFromConfig(listeners []EventListenerConfig) EventSink {
+	if len(listeners) == 0 {
+		return DefaultEventSink
+	}
+
+	sinks := make(multiEventSink, 0, len(listeners))
+	for _, l := range listeners {
+		sinks = append(sinks, NewWebhookEventSink(WebhookConfig{
+			Endpoint:   l.Endpoint,
+			Headers:    l.Headers,
+			Backoff:    l.Backoff,
+			MediaTypes: l.MediaTypes,
+		}))
+	}
+	return sinks
+}
+
+// multiEventSink fans a single Write out to every sink it holds, so a registry configured with several
+// event listeners can still be handed a single EventSink.
+type multiEventSink []EventSink
+
+func (m multiEventSink) Write(events ...Event) error {
+	var firstErr error
+	for _, sink := range m {
+		if err := sink.Write(events...); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// maxDeliveryAttempts bounds how many times webhookEventSink attempts a delivery in total (the first try
+// plus up to maxDeliveryAttempts-1 retries), so an endpoint
+// that is down indefinitely cannot grow an unbounded number of in-flight retry goroutines.
+const maxDeliveryAttempts = 3
+
+// webhookEventSink POSTs batches of events as JSON to a configured HTTP endpoint.
+type webhookEventSink struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookEventSink creates an EventSink that delivers events to an HTTP endpoint.
+func NewWebhookEventSink(config WebhookConfig) EventSink {
+	return &webhookEventSink{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Write filters events down to the ones this sink is configured for and hands them off to be delivered on
+// a separate goroutine, so a slow or unreachable webhook never stalls the request that produced the
+// events.
+func (s *webhookEventSink) Write(events ...Event) error {
+	var allowed []Event
+	for _, e := range events {
+		if s.allowed(e.MediaType) {
+			allowed = append(allowed, e)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(allowed)
+	if err != nil {
+		return err
+	}
+
+	go s.deliver(body)
+	return nil
+}
+
+// deliver POSTs body to the configured endpoint, making up to maxDeliveryAttempts attempts with
+// s.config.Backoff between them before giving up and logging the failure. It runs off the request's
+// goroutine, so failures can no longer be returned to a caller and are only logged.
+func (s *webhookEventSink) deliver(body []byte) {
+	var err error
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(s.config.Backoff)
+		}
+		if err = s.post(body); err == nil {
+			return
+		}
+	}
+	context.GetLogger(context.Background()).Errorf("event webhook %s: giving up after %d attempts: %v", s.config.Endpoint, maxDeliveryAttempts, err)
+}
+
+// post makes a single delivery attempt of body to the configured endpoint.
+func (s *webhookEventSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("event webhook %s responded with status %s", s.config.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookEventSink) allowed(mediaType string) bool {
+	if len(s.config.MediaTypes) == 0 {
+		return true
+	}
+	for _, mt := range s.config.MediaTypes {
+		if mt == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// EmitManifestEvent reports a manifest push, pull, or delete to sink. It is exported for the registry's
+// manifest service to call from its GET/PUT/DELETE handlers, the same way tagService reports tag
+// create/delete through createTagEvent and writeEvent.
+func EmitManifestEvent(ctx context.Context, sink EventSink, action EventAction, repository string, mediaType string, dgst digest.Digest, size int64) {
+	writeEvent(ctx, sink, Event{
+		Action:     action,
+		Repository: repository,
+		MediaType:  mediaType,
+		Digest:     dgst.String(),
+		Size:       size,
+	})
+}
+
+// writeEvent stamps event with its timestamp and, unless the caller already set them, the actor and
+// request ID the distribution auth and context middleware recorded on ctx. It then logs and discards any
+// error returned by sink.Write, so that a misbehaving or unreachable event listener never fails the
+// request that triggered the event.
+func writeEvent(ctx context.Context, sink EventSink, event Event) {
+	event.Timestamp = time.Now()
+	if event.Actor == "" {
+		event.Actor = actorFromContext(ctx)
+	}
+	if event.RequestID == "" {
+		event.RequestID = requestIDFromContext(ctx)
+	}
+	if err := sink.Write(event); err != nil {
+		context.GetLogger(ctx).Errorf("error delivering %s event for %s: %v", event.Action, event.Repository, err)
+	}
+}
+
+// actorFromContext extracts the authenticated username the distribution auth middleware records on ctx,
+// the same value the registry's access log uses to identify who made a request.
+func actorFromContext(ctx context.Context) string {
+	return context.GetStringValue(ctx, "auth.user.name")
+}
+
+// requestIDFromContext extracts the per-request ID the distribution HTTP context middleware assigns to
+// ctx, so an Event can be correlated back to the request that produced it in the registry's access log.
+func requestIDFromContext(ctx context.Context) string {
+	return context.GetStringValue(ctx, "http.request.id")
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/digest"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+// defaultImageCacheSize bounds the number of Image objects kept in the in-memory cache.
+const defaultImageCacheSize = 500
+
+// defaultImageCacheTTL bounds how long a cached Image is trusted before it is treated as a miss and
+// re-fetched, the same way defaultManifestCacheTTL bounds manifest entries.
+const defaultImageCacheTTL = 10 * time.Minute
+
+// imageCache is the process-wide cache tagService consults before calling imageStream.getImage, so that
+// repeated Get/Lookup/Untag calls for a digest that has already been resolved skip the Kubernetes API
+// round trip.
+var imageCache = newImageLRUCache(0)
+
+type imageCacheEntry struct {
+	dgst      digest.Digest
+	image     *imageapiv1.Image
+	expiresAt time.Time
+}
+
+// imageLRUCache is an in-memory, size- and TTL-bounded cache of Image objects keyed by digest. It is safe
+// for concurrent use.
+type imageLRUCache struct {
+	mu       sync.Mutex
+	maxItems int
+	ttl      time.Duration
+	entries  map[digest.Digest]*list.Element
+	order    *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// newImageLRUCache creates an image cache holding at most maxItems entries. A maxItems of 0 selects
+// defaultImageCacheSize.
+func newImageLRUCache(maxItems int) *imageLRUCache {
+	if maxItems <= 0 {
+		maxItems = defaultImageCacheSize
+	}
+	return &imageLRUCache{
+		maxItems: maxItems,
+		ttl:      defaultImageCacheTTL,
+		entries:  make(map[digest.Digest]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *imageLRUCache) Get(dgst digest.Digest) (*imageapiv1.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[dgst]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*imageCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, dgst)
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.image, true
+}
+
+func (c *imageLRUCache) Put(dgst digest.Digest, image *imageapiv1.Image) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[dgst]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*imageCacheEntry)
+		entry.image = image
+		entry.expiresAt = time.Now().Add(c.ttl)
+		return
+	}
+
+	elem := c.order.PushFront(&imageCacheEntry{
+		dgst:      dgst,
+		image:     image,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.entries[dgst] = elem
+
+	for c.order.Len() > c.maxItems {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*imageCacheEntry).dgst)
+	}
+}
+
+func (c *imageLRUCache) Delete(dgst digest.Digest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[dgst]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, dgst)
+}
+
+// Metrics returns the cumulative hit and miss counts observed by the cache.
+func (c *imageLRUCache) Metrics() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// ImageCacheStats returns the cumulative hit and miss counts for the process-wide image cache, for
+// exposing as Prometheus metrics alongside ManifestCacheStats.
+func ImageCacheStats() (hits, misses uint64) {
+	return imageCache.Metrics()
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/digest"
+)
+
+// defaultManifestCacheSize bounds the number of manifest payloads kept in the in-memory cache. Manifests
+// are small (a few KB at most), so this is a modest, fixed memory budget.
+const defaultManifestCacheSize = 500
+
+// defaultManifestCacheTTL bounds how long a cached manifest is trusted before it is treated as a miss and
+// re-fetched. Manifests are content-addressed, so a stale entry is never incorrect, but a TTL still
+// bounds how long a cache entry for a digest that has been garbage collected out of the image stream can
+// linger.
+const defaultManifestCacheTTL = 10 * time.Minute
+
+// manifestCache is the process-wide ManifestCache used by NewManifestFromImage and tagService.
+var manifestCache = NewManifestLRUCache(0)
+
+// ManifestCache stores manifest payloads keyed by their content digest. NewManifestFromImage reads and
+// fills it, and tagService removes entries on Untag. A hit still unmarshals the cached payload, and
+// NewManifestFromImage is only called with an Image its caller has already fetched, so a hit saves
+// neither the Image lookup nor the JSON unmarshal.
+type ManifestCache interface {
+	// Get returns the media type and payload cached for dgst, or ok=false if nothing is cached for it.
+	Get(dgst digest.Digest) (mediaType string, payload []byte, ok bool)
+
+	// Put stores the media type and payload for dgst, evicting the least recently used entry if the
+	// cache is full.
+	Put(dgst digest.Digest, mediaType string, payload []byte)
+
+	// Delete removes any cached entry for dgst. tagService calls it once no tag in the image stream refers
+	// to dgst any more, so a stale manifest cannot be served after the image it described is no longer
+	// reachable.
+	Delete(dgst digest.Digest)
+}
+
+type manifestCacheEntry struct {
+	dgst      digest.Digest
+	mediaType string
+	payload   []byte
+	expiresAt time.Time
+}
+
+// manifestLRUCache is an in-memory, size- and TTL-bounded ManifestCache. It is safe for concurrent use.
+type manifestLRUCache struct {
+	mu       sync.Mutex
+	maxItems int
+	ttl      time.Duration
+	entries  map[digest.Digest]*list.Element
+	order    *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// NewManifestLRUCache creates an in-memory ManifestCache holding at most maxItems manifest payloads, each
+// valid for defaultManifestCacheTTL. A maxItems of 0 selects defaultManifestCacheSize.
+func NewManifestLRUCache(maxItems int) ManifestCache {
+	if maxItems <= 0 {
+		maxItems = defaultManifestCacheSize
+	}
+	return &manifestLRUCache{
+		maxItems: maxItems,
+		ttl:      defaultManifestCacheTTL,
+		entries:  make(map[digest.Digest]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *manifestLRUCache) Get(dgst digest.Digest) (string, []byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[dgst]
+	if !ok {
+		c.misses++
+		return "", nil, false
+	}
+
+	entry := elem.Value.(*manifestCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, dgst)
+		c.misses++
+		return "", nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.mediaType, entry.payload, true
+}
+
+func (c *manifestLRUCache) Put(dgst digest.Digest, mediaType string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[dgst]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*manifestCacheEntry)
+		entry.mediaType = mediaType
+		entry.payload = payload
+		entry.expiresAt = time.Now().Add(c.ttl)
+		return
+	}
+
+	elem := c.order.PushFront(&manifestCacheEntry{
+		dgst:      dgst,
+		mediaType: mediaType,
+		payload:   payload,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.entries[dgst] = elem
+
+	for c.order.Len() > c.maxItems {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*manifestCacheEntry).dgst)
+	}
+}
+
+func (c *manifestLRUCache) Delete(dgst digest.Digest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[dgst]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, dgst)
+}
+
+// Metrics returns the cumulative hit and miss counts observed by the cache, for exposing as
+// registry metrics.
+func (c *manifestLRUCache) Metrics() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// ManifestCacheStats returns the cumulative hit and miss counts for the process-wide manifest cache, so a
+// registry can expose them as Prometheus metrics without needing access to the unexported cache
+// implementation.
+func ManifestCacheStats() (hits, misses uint64) {
+	if m, ok := manifestCache.(*manifestLRUCache); ok {
+		return m.Metrics()
+	}
+	return 0, 0
+}
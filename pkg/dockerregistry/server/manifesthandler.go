@@ -12,6 +12,13 @@ import (
 	imageapiv1 "github.com/openshift/api/image/v1"
 )
 
+// Values for the dockerLayersOrder annotation recorded on an image's layers, describing the order in
+// which a ManifestHandler's Layers() method lists them.
+const (
+	dockerLayersOrderAscending  = "ascending"
+	dockerLayersOrderDescending = "descending"
+)
+
 // A ManifestHandler defines a common set of operations on all versions of manifest schema.
 type ManifestHandler interface {
 	// Config returns a blob with image configuration associated with the manifest. This applies only to
@@ -24,7 +31,8 @@ type ManifestHandler interface {
 	// Manifest returns a deserialized manifest object.
 	Manifest() distribution.Manifest
 
-	// Layers returns image layers and a value for the dockerLayersOrder annotation.
+	// Layers returns image layers and a value for the dockerLayersOrder annotation. For manifest lists and
+	// image indexes this instead describes the child manifests referenced by the list.
 	Layers(ctx context.Context) (order string, layers []imageapiv1.ImageLayer, err error)
 
 	// Payload returns manifest's media type, complete payload with signatures and canonical payload without
@@ -33,18 +41,100 @@ type ManifestHandler interface {
 
 	// Verify returns an error if the contained manifest is not valid or has missing dependencies.
 	Verify(ctx context.Context, skipDependencyVerification bool) error
+
+	// Etag returns a value suitable for an HTTP ETag header, so a client's If-None-Match can be compared
+	// against it without re-serializing Payload(). The canonical digest already uniquely identifies the
+	// manifest's content, so it doubles as the ETag value.
+	Etag() (string, error)
+}
+
+// etagFromHandler computes the ETag for any ManifestHandler from its Digest(), saving each schema's
+// Etag() implementation from having to duplicate that logic.
+func etagFromHandler(h ManifestHandler) (string, error) {
+	dgst, err := h.Digest()
+	if err != nil {
+		return "", err
+	}
+	return dgst.String(), nil
+}
+
+// EtagMatches reports whether ifNoneMatch (the value of an incoming If-None-Match header) matches h's
+// ETag, meaning the caller already has the current representation and a 304 Not Modified can be returned
+// instead of re-fetching and re-serializing the manifest.
+func EtagMatches(h ManifestHandler, ifNoneMatch string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag, err := h.Etag()
+	if err != nil {
+		return false
+	}
+	return ifNoneMatch == etag
+}
+
+// unmarshalManifestFunc deserializes a manifest payload (and its detached signatures, where applicable)
+// into a distribution.Manifest of the schema it was registered for.
+type unmarshalManifestFunc func(content []byte, signatures []string) (distribution.Manifest, error)
+
+// newManifestHandlerFunc builds a ManifestHandler for a manifest of the schema it was registered for.
+type newManifestHandlerFunc func(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error)
+
+type manifestSchema struct {
+	unmarshal  unmarshalManifestFunc
+	newHandler newManifestHandlerFunc
+}
+
+// manifestSchemas maps a manifest media type to the functions able to unmarshal and handle it. Schemas
+// register themselves through RegisterManifestHandler, usually from an init() function, so that the core
+// of the registry never needs to know about concrete manifest types.
+var manifestSchemas = map[string]manifestSchema{}
+
+// RegisterManifestHandler associates a manifest media type with the functions used to unmarshal its
+// payload and to construct a ManifestHandler around it. It is meant to be called from the init() function
+// of the package implementing support for that media type. Registering the same media type twice panics,
+// since it is always a programmer error.
+func RegisterManifestHandler(mediaType string, unmarshal unmarshalManifestFunc, newHandler newManifestHandlerFunc) {
+	if _, ok := manifestSchemas[mediaType]; ok {
+		panic(fmt.Sprintf("manifest handler for media type %s already registered", mediaType))
+	}
+	manifestSchemas[mediaType] = manifestSchema{unmarshal: unmarshal, newHandler: newHandler}
+}
+
+func init() {
+	RegisterManifestHandler(schema1.MediaTypeManifest, unmarshalManifestSchema1, newManifestSchema1Handler)
+	RegisterManifestHandler(schema2.MediaTypeManifest, unmarshalManifestSchema2, newManifestSchema2Handler)
+}
+
+func newManifestSchema1Handler(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error) {
+	t, ok := manifest.(*schema1.SignedManifest)
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest type %T for media type %s", manifest, schema1.MediaTypeManifest)
+	}
+	return &manifestSchema1Handler{serverAddr: serverAddr, blobStore: blobStore, manifest: t}, nil
+}
+
+func newManifestSchema2Handler(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error) {
+	t, ok := manifest.(*schema2.DeserializedManifest)
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest type %T for media type %s", manifest, schema2.MediaTypeManifest)
+	}
+	return &manifestSchema2Handler{blobStore: blobStore, manifest: t}, nil
 }
 
-// NewManifestHandler creates a manifest handler for the given manifest.
+// NewManifestHandler creates a manifest handler for the given manifest, looking up the constructor
+// registered for the manifest's media type.
 func NewManifestHandler(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error) {
-	switch t := manifest.(type) {
-	case *schema1.SignedManifest:
-		return &manifestSchema1Handler{serverAddr: serverAddr, blobStore: blobStore, manifest: t}, nil
-	case *schema2.DeserializedManifest:
-		return &manifestSchema2Handler{blobStore: blobStore, manifest: t}, nil
-	default:
-		return nil, fmt.Errorf("unsupported manifest type %T", manifest)
+	mediaType, _, err := manifest.Payload()
+	if err != nil {
+		return nil, err
 	}
+
+	schema, ok := manifestSchemas[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest media type %s", mediaType)
+	}
+
+	return schema.newHandler(serverAddr, blobStore, manifest)
 }
 
 // NewManifestFromImage creates a manifest for a manifest stored in the given image.
@@ -53,12 +143,32 @@ func NewManifestFromImage(image *imageapiv1.Image) (distribution.Manifest, error
 		return nil, fmt.Errorf("manifest is not present in image object %s (mediatype=%q)", image.Name, image.DockerImageManifestMediaType)
 	}
 
-	switch image.DockerImageManifestMediaType {
-	case "", schema1.MediaTypeManifest:
-		return unmarshalManifestSchema1([]byte(image.DockerImageManifest), image.DockerImageSignatures)
-	case schema2.MediaTypeManifest:
-		return unmarshalManifestSchema2([]byte(image.DockerImageManifest))
-	default:
-		return nil, fmt.Errorf("unsupported manifest media type %s", image.DockerImageManifestMediaType)
+	mediaType := image.DockerImageManifestMediaType
+	if mediaType == "" {
+		mediaType = schema1.MediaTypeManifest
 	}
+
+	schema, ok := manifestSchemas[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest media type %s", mediaType)
+	}
+
+	if dgst, err := digest.ParseDigest(image.Name); err == nil {
+		if cachedMediaType, payload, ok := manifestCache.Get(dgst); ok {
+			if cachedSchema, ok := manifestSchemas[cachedMediaType]; ok {
+				return cachedSchema.unmarshal(payload, image.DockerImageSignatures)
+			}
+		}
+	}
+
+	manifest, err := schema.unmarshal([]byte(image.DockerImageManifest), image.DockerImageSignatures)
+	if err != nil {
+		return nil, err
+	}
+
+	if dgst, err := digest.ParseDigest(image.Name); err == nil {
+		manifestCache.Put(dgst, mediaType, []byte(image.DockerImageManifest))
+	}
+
+	return manifest, nil
 }
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+// fakeBlobStore is a distribution.BlobStore stand-in backed by an in-memory map, used by the schema1,
+// schema2, and OCI handler tests to exercise Config() and Verify() without a real registry storage
+// backend. Only Stat and Get are meaningful; the rest of the interface is never exercised by a
+// ManifestHandler and returns an error if it is.
+type fakeBlobStore struct {
+	blobs map[digest.Digest][]byte
+}
+
+func (s *fakeBlobStore) Stat(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+	blob, ok := s.blobs[dgst]
+	if !ok {
+		return distribution.Descriptor{}, distribution.ErrBlobUnknown
+	}
+	return distribution.Descriptor{Digest: dgst, Size: int64(len(blob))}, nil
+}
+
+func (s *fakeBlobStore) Get(ctx context.Context, dgst digest.Digest) ([]byte, error) {
+	blob, ok := s.blobs[dgst]
+	if !ok {
+		return nil, distribution.ErrBlobUnknown
+	}
+	return blob, nil
+}
+
+func (s *fakeBlobStore) Open(ctx context.Context, dgst digest.Digest) (io.ReadSeekCloser, error) {
+	return nil, fmt.Errorf("fakeBlobStore: Open not implemented")
+}
+
+func (s *fakeBlobStore) Put(ctx context.Context, mediaType string, p []byte) (distribution.Descriptor, error) {
+	return distribution.Descriptor{}, fmt.Errorf("fakeBlobStore: Put not implemented")
+}
+
+func (s *fakeBlobStore) Create(ctx context.Context, options ...distribution.BlobCreateOption) (distribution.BlobWriter, error) {
+	return nil, fmt.Errorf("fakeBlobStore: Create not implemented")
+}
+
+func (s *fakeBlobStore) Resume(ctx context.Context, id string) (distribution.BlobWriter, error) {
+	return nil, fmt.Errorf("fakeBlobStore: Resume not implemented")
+}
+
+func (s *fakeBlobStore) ServeBlob(ctx context.Context, w http.ResponseWriter, r *http.Request, dgst digest.Digest) error {
+	return fmt.Errorf("fakeBlobStore: ServeBlob not implemented")
+}
+
+func (s *fakeBlobStore) Delete(ctx context.Context, dgst digest.Digest) error {
+	return fmt.Errorf("fakeBlobStore: Delete not implemented")
+}
+
+// fakeManifestHandler is a minimal ManifestHandler stand-in used to exercise EtagMatches without needing
+// a real blob store or manifest payload.
+type fakeManifestHandler struct {
+	etag    string
+	etagErr error
+}
+
+func (f *fakeManifestHandler) Config(ctx context.Context) ([]byte, error) { return nil, nil }
+func (f *fakeManifestHandler) Digest() (digest.Digest, error) { return "", nil }
+func (f *fakeManifestHandler) Manifest() distribution.Manifest { return nil }
+func (f *fakeManifestHandler) Layers(ctx context.Context) (string, []imageapiv1.ImageLayer, error) {
+	return "", nil, nil
+}
+func (f *fakeManifestHandler) Payload() (string, []byte, []byte, error) { return "", nil, nil, nil }
+func (f *fakeManifestHandler) Verify(ctx context.Context, skip bool) error { return nil }
+func (f *fakeManifestHandler) Etag() (string, error) { return f.etag, f.etagErr }
+
+func TestEtagMatches(t *testing.T) {
+	h := &fakeManifestHandler{etag: "sha256:abc"}
+
+	if EtagMatches(h, "") {
+		t.Errorf("expected no match for an empty If-None-Match header")
+	}
+	if EtagMatches(h, "sha256:def") {
+		t.Errorf("expected no match for a different digest")
+	}
+	if !EtagMatches(h, "sha256:abc") {
+		t.Errorf("expected a match for the handler's own digest")
+	}
+}
+
+func TestEtagMatchesHandlerError(t *testing.T) {
+	h := &fakeManifestHandler{etagErr: distribution.ErrBlobUnknown}
+
+	if EtagMatches(h, "sha256:abc") {
+		t.Errorf("expected no match when Etag() returns an error")
+	}
+}
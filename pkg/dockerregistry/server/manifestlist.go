@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+func init() {
+	RegisterManifestHandler(manifestlist.MediaTypeManifestList, unmarshalManifestList, newManifestListHandler)
+	RegisterManifestHandler(manifestlist.MediaTypeImageIndex, unmarshalManifestList, newManifestListHandler)
+}
+
+// unmarshalManifestList unmarshals a payload into a manifest list or an OCI image index. Both media types
+// share the same on-disk representation in github.com/docker/distribution/manifest/manifestlist,
+// distinguished only by the media type recorded on the deserialized manifest itself.
+func unmarshalManifestList(content []byte, signatures []string) (distribution.Manifest, error) {
+	m := &manifestlist.DeserializedManifestList{}
+	if err := m.UnmarshalJSON(content); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestListHandler is a ManifestHandler for manifest lists (application/vnd.docker.distribution.manifest.list.v2+json)
+// and OCI image indexes (application/vnd.oci.image.index.v1+json). Unlike the single-image manifest
+// handlers, it has no config blob or layers of its own; it only references other manifests.
+type manifestListHandler struct {
+	serverAddr string
+	blobStore  distribution.BlobStore
+	manifest   *manifestlist.DeserializedManifestList
+}
+
+func newManifestListHandler(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error) {
+	t, ok := manifest.(*manifestlist.DeserializedManifestList)
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest type %T for a manifest list", manifest)
+	}
+	return &manifestListHandler{serverAddr: serverAddr, blobStore: blobStore, manifest: t}, nil
+}
+
+// Config is not applicable to manifest lists: they have no image configuration of their own, only
+// references to the manifests of the images they describe.
+func (h *manifestListHandler) Config(ctx context.Context) ([]byte, error) {
+	return nil, nil
+}
+
+func (h *manifestListHandler) Digest() (digest.Digest, error) {
+	_, _, canonical, err := h.Payload()
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(canonical), nil
+}
+
+func (h *manifestListHandler) Manifest() distribution.Manifest {
+	return h.manifest
+}
+
+func (h *manifestListHandler) Etag() (string, error) {
+	return etagFromHandler(h)
+}
+
+// Layers returns the manifest list's child manifest descriptors rather than image layers: one entry per
+// platform-specific manifest referenced by the list.
+func (h *manifestListHandler) Layers(ctx context.Context) (string, []imageapiv1.ImageLayer, error) {
+	descriptors := h.manifest.References()
+	layers := make([]imageapiv1.ImageLayer, len(descriptors))
+	for i, d := range descriptors {
+		layers[i] = imageapiv1.ImageLayer{
+			Name:      d.Digest.String(),
+			LayerSize: d.Size,
+			MediaType: d.MediaType,
+		}
+	}
+	return dockerLayersOrderAscending, layers, nil
+}
+
+func (h *manifestListHandler) Payload() (string, []byte, []byte, error) {
+	mediaType, payload, err := h.manifest.Payload()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return mediaType, payload, payload, nil
+}
+
+// Verify checks that every manifest referenced by the list is itself present and valid: it resolves each
+// child digest to an Image through manifestListImageGetter, builds the child's manifest and handler with
+// NewManifestFromImage and NewManifestHandler, and verifies that handler. A manifest list's children are
+// other manifests, stored as Image objects, not blobs in h.blobStore. With the default
+// manifestListImageGetter every child fails with errManifestListVerificationNotSupported.
+func (h *manifestListHandler) Verify(ctx context.Context, skipDependencyVerification bool) error {
+	if skipDependencyVerification {
+		return nil
+	}
+
+	var errs distribution.ErrManifestVerification
+
+	for _, d := range h.manifest.References() {
+		image, err := manifestListImageGetter(ctx, d.Digest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		childManifest, err := NewManifestFromImage(image)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		childHandler, err := NewManifestHandler(h.serverAddr, h.blobStore, childManifest)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := childHandler.Verify(ctx, skipDependencyVerification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// errManifestListVerificationNotSupported is returned by the default manifestListImageGetter. A manifest
+// list's children are other manifests, stored as Image objects rather than blobs, so resolving them
+// requires a lookup scoped to the image stream the list was pushed to — something only the repository
+// constructing the handler knows how to do. Until a repository installs that lookup, Verify fails with
+// this error instead of silently checking d.Digest against h.blobStore, which never holds manifest
+// objects and so could never have passed verification anyway.
+var errManifestListVerificationNotSupported = fmt.Errorf("verifying manifest list children is not supported yet")
+
+// manifestListImageGetter resolves a manifest list's child manifest digest to its Image for Verify. The
+// default returns errManifestListVerificationNotSupported for every digest, and nothing in this package
+// replaces it, so Verify reports that error rather than pretending to have checked anything.
+var manifestListImageGetter = func(ctx context.Context, dgst digest.Digest) (*imageapiv1.Image, error) {
+	return nil, errManifestListVerificationNotSupported
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema2"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+const manifestListTestChildDigest = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+func newTestManifestListHandler(t *testing.T) *manifestListHandler {
+	t.Helper()
+
+	m := &manifestlist.DeserializedManifestList{
+		ManifestList: manifestlist.ManifestList{
+			Manifests: []manifestlist.ManifestDescriptor{
+				{
+					Descriptor: distribution.Descriptor{
+						Digest:    digest.Digest(manifestListTestChildDigest),
+						MediaType: schema2.MediaTypeManifest,
+					},
+				},
+			},
+		},
+	}
+
+	return &manifestListHandler{blobStore: &fakeBlobStore{blobs: map[digest.Digest][]byte{}}, manifest: m}
+}
+
+// withManifestListImageGetter overrides the package-level manifestListImageGetter for the duration of a
+// test and restores the previous value afterwards, since it is process-global state shared by every
+// manifestListHandler.
+func withManifestListImageGetter(t *testing.T, getter func(context.Context, digest.Digest) (*imageapiv1.Image, error)) {
+	t.Helper()
+
+	previous := manifestListImageGetter
+	manifestListImageGetter = getter
+	t.Cleanup(func() { manifestListImageGetter = previous })
+}
+
+func TestManifestListHandlerVerifyUnconfiguredGetter(t *testing.T) {
+	h := newTestManifestListHandler(t)
+
+	err := h.Verify(context.Background(), false)
+	if err == nil {
+		t.Fatalf("expected Verify to fail when no manifestListImageGetter is configured")
+	}
+	verrs, ok := err.(distribution.ErrManifestVerification)
+	if !ok || len(verrs) != 1 || verrs[0] != errManifestListVerificationNotSupported {
+		t.Errorf("Verify() = %v, want a single errManifestListVerificationNotSupported", err)
+	}
+}
+
+func TestManifestListHandlerVerifyGetterError(t *testing.T) {
+	h := newTestManifestListHandler(t)
+
+	getterErr := fmt.Errorf("image lookup failed")
+	withManifestListImageGetter(t, func(ctx context.Context, dgst digest.Digest) (*imageapiv1.Image, error) {
+		return nil, getterErr
+	})
+
+	err := h.Verify(context.Background(), false)
+	if err == nil {
+		t.Fatalf("expected Verify to fail when manifestListImageGetter errors")
+	}
+	verrs, ok := err.(distribution.ErrManifestVerification)
+	if !ok || len(verrs) != 1 || verrs[0] != getterErr {
+		t.Errorf("Verify() = %v, want a single %v", err, getterErr)
+	}
+}
+
+func TestManifestListHandlerVerifyGetterConfigured(t *testing.T) {
+	h := newTestManifestListHandler(t)
+
+	image := &imageapiv1.Image{
+		DockerImageManifestMediaType: schema2.MediaTypeManifest,
+		DockerImageManifest:          []byte(schema2TestManifest),
+	}
+	image.Name = manifestListTestChildDigest
+
+	withManifestListImageGetter(t, func(ctx context.Context, dgst digest.Digest) (*imageapiv1.Image, error) {
+		if dgst.String() != manifestListTestChildDigest {
+			return nil, fmt.Errorf("unexpected digest %s", dgst)
+		}
+		return image, nil
+	})
+
+	h.blobStore.(*fakeBlobStore).blobs[digest.Digest(schema2TestConfigDigest)] = []byte("fake-config-blob")
+	h.blobStore.(*fakeBlobStore).blobs[digest.Digest(schema2TestLayerDigest)] = []byte("fake-layer-blob")
+
+	if err := h.Verify(context.Background(), false); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestManifestListHandlerVerifySkipsDependencyVerification(t *testing.T) {
+	h := newTestManifestListHandler(t)
+
+	if err := h.Verify(context.Background(), true); err != nil {
+		t.Errorf("Verify with skipDependencyVerification=true should always succeed: %v", err)
+	}
+}
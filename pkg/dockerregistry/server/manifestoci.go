@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/ocischema"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+func init() {
+	RegisterManifestHandler(ocischema.MediaTypeManifest, unmarshalManifestOCI, newManifestOCIHandler)
+}
+
+// unmarshalManifestOCI unmarshals a payload into an OCI image manifest. OCI image manifests carry no
+// detached signatures.
+func unmarshalManifestOCI(content []byte, signatures []string) (distribution.Manifest, error) {
+	m, _, err := distribution.UnmarshalManifest(ocischema.MediaTypeManifest, content)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestOCIHandler is a ManifestHandler for the OCI image manifest media type. The OCI image manifest
+// format is structurally identical to Docker's schema 2 manifest, so its semantics mirror
+// manifestSchema2Handler.
+type manifestOCIHandler struct {
+	blobStore distribution.BlobStore
+	manifest  *ocischema.DeserializedManifest
+}
+
+func newManifestOCIHandler(serverAddr string, blobStore distribution.BlobStore, manifest distribution.Manifest) (ManifestHandler, error) {
+	t, ok := manifest.(*ocischema.DeserializedManifest)
+	if !ok {
+		return nil, fmt.Errorf("unsupported manifest type %T for media type %s", manifest, ocischema.MediaTypeManifest)
+	}
+	return &manifestOCIHandler{blobStore: blobStore, manifest: t}, nil
+}
+
+func (h *manifestOCIHandler) Config(ctx context.Context) ([]byte, error) {
+	if h.manifest.Config.Digest == "" {
+		return nil, nil
+	}
+	return h.blobStore.Get(ctx, h.manifest.Config.Digest)
+}
+
+func (h *manifestOCIHandler) Digest() (digest.Digest, error) {
+	_, _, canonical, err := h.Payload()
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(canonical), nil
+}
+
+func (h *manifestOCIHandler) Manifest() distribution.Manifest {
+	return h.manifest
+}
+
+func (h *manifestOCIHandler) Etag() (string, error) {
+	return etagFromHandler(h)
+}
+
+func (h *manifestOCIHandler) Layers(ctx context.Context) (string, []imageapiv1.ImageLayer, error) {
+	layers := make([]imageapiv1.ImageLayer, len(h.manifest.Layers))
+	for i, layer := range h.manifest.Layers {
+		layers[i] = imageapiv1.ImageLayer{
+			Name:      layer.Digest.String(),
+			LayerSize: layer.Size,
+			MediaType: layer.MediaType,
+		}
+	}
+	return dockerLayersOrderAscending, layers, nil
+}
+
+func (h *manifestOCIHandler) Payload() (string, []byte, []byte, error) {
+	mediaType, payload, err := h.manifest.Payload()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return mediaType, payload, payload, nil
+}
+
+func (h *manifestOCIHandler) Verify(ctx context.Context, skipDependencyVerification bool) error {
+	if skipDependencyVerification {
+		return nil
+	}
+
+	var errs distribution.ErrManifestVerification
+
+	if h.manifest.Config.Digest != "" {
+		if _, err := h.blobStore.Stat(ctx, h.manifest.Config.Digest); err != nil {
+			if err != distribution.ErrBlobUnknown {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, distribution.ErrManifestBlobUnknown{Digest: h.manifest.Config.Digest})
+			}
+		}
+	}
+
+	for _, layer := range h.manifest.Layers {
+		if _, err := h.blobStore.Stat(ctx, layer.Digest); err != nil {
+			if err != distribution.ErrBlobUnknown {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, distribution.ErrManifestBlobUnknown{Digest: layer.Digest})
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/ocischema"
+)
+
+const ociTestConfigDigest = "sha256:a656c360a37a0e8f4d70596a292ffcb1fa7a16ac97d6a49caf2b8a8c77751cd4"
+const ociTestLayerDigest = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+const ociTestManifest = `{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.oci.image.manifest.v1+json",
+	"config": {
+		"mediaType": "application/vnd.oci.image.config.v1+json",
+		"size": 16,
+		"digest": "` + ociTestConfigDigest + `"
+	},
+	"layers": [
+		{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"size": 15,
+			"digest": "` + ociTestLayerDigest + `"
+		}
+	]
+}`
+
+func newTestManifestOCIHandler(t *testing.T, blobStore *fakeBlobStore) ManifestHandler {
+	t.Helper()
+
+	manifest, err := unmarshalManifestOCI([]byte(ociTestManifest), nil)
+	if err != nil {
+		t.Fatalf("unmarshalManifestOCI: %v", err)
+	}
+
+	h, err := newManifestOCIHandler("", blobStore, manifest)
+	if err != nil {
+		t.Fatalf("newManifestOCIHandler: %v", err)
+	}
+	return h
+}
+
+func TestManifestOCIHandlerPayloadAndLayers(t *testing.T) {
+	h := newTestManifestOCIHandler(t, &fakeBlobStore{blobs: map[digest.Digest][]byte{}})
+
+	mediaType, payload, canonical, err := h.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	if mediaType != ocischema.MediaTypeManifest {
+		t.Errorf("mediaType = %q, want %q", mediaType, ocischema.MediaTypeManifest)
+	}
+	if len(payload) == 0 || len(canonical) == 0 {
+		t.Errorf("expected non-empty payload and canonical bytes")
+	}
+
+	order, layers, err := h.Layers(context.Background())
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	if order != dockerLayersOrderAscending {
+		t.Errorf("order = %q, want %q", order, dockerLayersOrderAscending)
+	}
+	if len(layers) != 1 || layers[0].Name != ociTestLayerDigest {
+		t.Errorf("layers = %+v, want a single layer named %q", layers, ociTestLayerDigest)
+	}
+}
+
+func TestManifestOCIHandlerVerify(t *testing.T) {
+	store := &fakeBlobStore{blobs: map[digest.Digest][]byte{}}
+	h := newTestManifestOCIHandler(t, store)
+
+	if err := h.Verify(context.Background(), false); err == nil {
+		t.Errorf("expected Verify to fail when the config and layer blobs are missing")
+	}
+
+	store.blobs[digest.Digest(ociTestConfigDigest)] = []byte("fake-config-blob")
+	store.blobs[digest.Digest(ociTestLayerDigest)] = []byte("fake-layer-blob")
+
+	if err := h.Verify(context.Background(), false); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	if err := h.Verify(context.Background(), true); err != nil {
+		t.Errorf("Verify with skipDependencyVerification=true should always succeed: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema1"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+// unmarshalManifestSchema1 unmarshals a payload into a signed schema 1 manifest. signatures is unused:
+// every schema 1 payload already carries its own signature block embedded as a JWS, which
+// SignedManifest.UnmarshalJSON parses out into Canonical.
+func unmarshalManifestSchema1(content []byte, signatures []string) (distribution.Manifest, error) {
+	m := &schema1.SignedManifest{}
+	if err := m.UnmarshalJSON(content); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestSchema1Handler is a ManifestHandler for the legacy, signed Docker manifest schema 1. Schema 1
+// manifests carry no separate image configuration; FSLayers already double as the image layers.
+type manifestSchema1Handler struct {
+	serverAddr string
+	blobStore  distribution.BlobStore
+	manifest   *schema1.SignedManifest
+}
+
+// Config is not applicable to manifest schema 1: it has no image configuration blob, only FSLayers.
+func (h *manifestSchema1Handler) Config(ctx context.Context) ([]byte, error) {
+	return nil, nil
+}
+
+func (h *manifestSchema1Handler) Digest() (digest.Digest, error) {
+	_, _, canonical, err := h.Payload()
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(canonical), nil
+}
+
+func (h *manifestSchema1Handler) Manifest() distribution.Manifest {
+	return h.manifest
+}
+
+// Layers returns the manifest's FSLayers. Schema 1 lists them newest first, the opposite of schema 2.
+func (h *manifestSchema1Handler) Layers(ctx context.Context) (string, []imageapiv1.ImageLayer, error) {
+	layers := make([]imageapiv1.ImageLayer, len(h.manifest.FSLayers))
+	for i, layer := range h.manifest.FSLayers {
+		layers[i] = imageapiv1.ImageLayer{
+			Name: layer.BlobSum.String(),
+		}
+	}
+	return dockerLayersOrderDescending, layers, nil
+}
+
+func (h *manifestSchema1Handler) Payload() (string, []byte, []byte, error) {
+	mediaType, payload, err := h.manifest.Payload()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return mediaType, payload, h.manifest.Canonical, nil
+}
+
+func (h *manifestSchema1Handler) Etag() (string, error) {
+	return etagFromHandler(h)
+}
+
+func (h *manifestSchema1Handler) Verify(ctx context.Context, skipDependencyVerification bool) error {
+	if skipDependencyVerification {
+		return nil
+	}
+
+	var errs distribution.ErrManifestVerification
+
+	for _, fsLayer := range h.manifest.FSLayers {
+		if _, err := h.blobStore.Stat(ctx, fsLayer.BlobSum); err != nil {
+			if err != distribution.ErrBlobUnknown {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, distribution.ErrManifestBlobUnknown{Digest: fsLayer.BlobSum})
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema1"
+)
+
+const schema1TestLayerDigest = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+// newTestManifestSchema1Handler builds a manifestSchema1Handler around a SignedManifest constructed
+// directly rather than through unmarshalManifestSchema1, since a realistic JWS-signed fixture would need
+// the same libtrust signing machinery the registry itself uses to produce one. Digest(), Payload(), and
+// Layers() only depend on the fields set here.
+func newTestManifestSchema1Handler(t *testing.T, blobStore *fakeBlobStore) ManifestHandler {
+	t.Helper()
+
+	manifest := &schema1.SignedManifest{
+		Manifest: schema1.Manifest{
+			Name: "test/repo",
+			Tag:  "latest",
+			FSLayers: []schema1.FSLayer{
+				{BlobSum: digest.Digest(schema1TestLayerDigest)},
+			},
+		},
+		Raw:       []byte(`{"fake":"raw"}`),
+		Canonical: []byte(`{"fake":"canonical"}`),
+	}
+
+	h, err := newManifestSchema1Handler("", blobStore, manifest)
+	if err != nil {
+		t.Fatalf("newManifestSchema1Handler: %v", err)
+	}
+	return h
+}
+
+func TestManifestSchema1HandlerPayloadAndLayers(t *testing.T) {
+	h := newTestManifestSchema1Handler(t, &fakeBlobStore{blobs: map[digest.Digest][]byte{}})
+
+	mediaType, payload, canonical, err := h.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	if mediaType != schema1.MediaTypeManifest {
+		t.Errorf("mediaType = %q, want %q", mediaType, schema1.MediaTypeManifest)
+	}
+	if len(payload) == 0 || len(canonical) == 0 {
+		t.Errorf("expected non-empty payload and canonical bytes")
+	}
+
+	order, layers, err := h.Layers(context.Background())
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	if order != dockerLayersOrderDescending {
+		t.Errorf("order = %q, want %q", order, dockerLayersOrderDescending)
+	}
+	if len(layers) != 1 || layers[0].Name != schema1TestLayerDigest {
+		t.Errorf("layers = %+v, want a single layer named %q", layers, schema1TestLayerDigest)
+	}
+
+	dgst, err := h.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	if dgst.String() == "" {
+		t.Errorf("expected a non-empty digest")
+	}
+}
+
+func TestManifestSchema1HandlerVerify(t *testing.T) {
+	store := &fakeBlobStore{blobs: map[digest.Digest][]byte{}}
+	h := newTestManifestSchema1Handler(t, store)
+
+	if err := h.Verify(context.Background(), false); err == nil {
+		t.Errorf("expected Verify to fail when the FSLayer blob is missing")
+	}
+
+	store.blobs[digest.Digest(schema1TestLayerDigest)] = []byte("fake-layer-blob")
+
+	if err := h.Verify(context.Background(), false); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+)
+
+// unmarshalManifestSchema2 unmarshals a payload into a schema 2 manifest. signatures is unused: schema 2
+// manifests carry no detached signatures of their own.
+func unmarshalManifestSchema2(content []byte, signatures []string) (distribution.Manifest, error) {
+	m := &schema2.DeserializedManifest{}
+	if err := m.UnmarshalJSON(content); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// manifestSchema2Handler is a ManifestHandler for Docker manifest schema 2.
+type manifestSchema2Handler struct {
+	blobStore distribution.BlobStore
+	manifest  *schema2.DeserializedManifest
+}
+
+func (h *manifestSchema2Handler) Config(ctx context.Context) ([]byte, error) {
+	return h.blobStore.Get(ctx, h.manifest.Config.Digest)
+}
+
+func (h *manifestSchema2Handler) Digest() (digest.Digest, error) {
+	_, _, canonical, err := h.Payload()
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(canonical), nil
+}
+
+func (h *manifestSchema2Handler) Manifest() distribution.Manifest {
+	return h.manifest
+}
+
+func (h *manifestSchema2Handler) Layers(ctx context.Context) (string, []imageapiv1.ImageLayer, error) {
+	layers := make([]imageapiv1.ImageLayer, len(h.manifest.Layers))
+	for i, layer := range h.manifest.Layers {
+		layers[i] = imageapiv1.ImageLayer{
+			Name:      layer.Digest.String(),
+			LayerSize: layer.Size,
+			MediaType: layer.MediaType,
+		}
+	}
+	return dockerLayersOrderAscending, layers, nil
+}
+
+func (h *manifestSchema2Handler) Payload() (string, []byte, []byte, error) {
+	mediaType, payload, err := h.manifest.Payload()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return mediaType, payload, payload, nil
+}
+
+func (h *manifestSchema2Handler) Etag() (string, error) {
+	return etagFromHandler(h)
+}
+
+func (h *manifestSchema2Handler) Verify(ctx context.Context, skipDependencyVerification bool) error {
+	if skipDependencyVerification {
+		return nil
+	}
+
+	var errs distribution.ErrManifestVerification
+
+	if _, err := h.blobStore.Stat(ctx, h.manifest.Config.Digest); err != nil {
+		if err != distribution.ErrBlobUnknown {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, distribution.ErrManifestBlobUnknown{Digest: h.manifest.Config.Digest})
+		}
+	}
+
+	for _, layer := range h.manifest.Layers {
+		if _, err := h.blobStore.Stat(ctx, layer.Digest); err != nil {
+			if err != distribution.ErrBlobUnknown {
+				errs = append(errs, err)
+			} else {
+				errs = append(errs, distribution.ErrManifestBlobUnknown{Digest: layer.Digest})
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
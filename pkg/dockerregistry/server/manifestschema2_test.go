@@ -0,0 +1,102 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/context"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+)
+
+const schema2TestConfigDigest = "sha256:a656c360a37a0e8f4d70596a292ffcb1fa7a16ac97d6a49caf2b8a8c77751cd4"
+const schema2TestLayerDigest = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+const schema2TestManifest = `{
+	"schemaVersion": 2,
+	"mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+	"config": {
+		"mediaType": "application/vnd.docker.container.image.v1+json",
+		"size": 16,
+		"digest": "` + schema2TestConfigDigest + `"
+	},
+	"layers": [
+		{
+			"mediaType": "application/vnd.docker.image.rootfs.diff.tar.gzip",
+			"size": 15,
+			"digest": "` + schema2TestLayerDigest + `"
+		}
+	]
+}`
+
+func newTestManifestSchema2Handler(t *testing.T, blobStore *fakeBlobStore) ManifestHandler {
+	t.Helper()
+
+	manifest, err := unmarshalManifestSchema2([]byte(schema2TestManifest), nil)
+	if err != nil {
+		t.Fatalf("unmarshalManifestSchema2: %v", err)
+	}
+
+	h, err := newManifestSchema2Handler("", blobStore, manifest)
+	if err != nil {
+		t.Fatalf("newManifestSchema2Handler: %v", err)
+	}
+	return h
+}
+
+func TestManifestSchema2HandlerPayloadAndLayers(t *testing.T) {
+	h := newTestManifestSchema2Handler(t, &fakeBlobStore{blobs: map[digest.Digest][]byte{}})
+
+	mediaType, payload, canonical, err := h.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	if mediaType != schema2.MediaTypeManifest {
+		t.Errorf("mediaType = %q, want %q", mediaType, schema2.MediaTypeManifest)
+	}
+	if len(payload) == 0 || len(canonical) == 0 {
+		t.Errorf("expected non-empty payload and canonical bytes")
+	}
+
+	order, layers, err := h.Layers(context.Background())
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	if order != dockerLayersOrderAscending {
+		t.Errorf("order = %q, want %q", order, dockerLayersOrderAscending)
+	}
+	if len(layers) != 1 || layers[0].Name != schema2TestLayerDigest {
+		t.Errorf("layers = %+v, want a single layer named %q", layers, schema2TestLayerDigest)
+	}
+
+	dgst, err := h.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	if dgst.String() == "" {
+		t.Errorf("expected a non-empty digest")
+	}
+
+	etag, err := h.Etag()
+	if err != nil {
+		t.Fatalf("Etag: %v", err)
+	}
+	if etag != dgst.String() {
+		t.Errorf("Etag() = %q, want it to match Digest() %q", etag, dgst.String())
+	}
+}
+
+func TestManifestSchema2HandlerVerify(t *testing.T) {
+	store := &fakeBlobStore{blobs: map[digest.Digest][]byte{}}
+	h := newTestManifestSchema2Handler(t, store)
+
+	if err := h.Verify(context.Background(), false); err == nil {
+		t.Errorf("expected Verify to fail when the config and layer blobs are missing")
+	}
+
+	store.blobs[digest.Digest(schema2TestConfigDigest)] = []byte("fake-config-blob")
+	store.blobs[digest.Digest(schema2TestLayerDigest)] = []byte("fake-layer-blob")
+
+	if err := h.Verify(context.Background(), false); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
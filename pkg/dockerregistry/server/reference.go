@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/docker/distribution/digest"
+)
+
+// Reference is either a tag name or a digest, as accepted anywhere the v2 manifest route's "reference"
+// path segment is (tag|digest). tagService historically only handled tag names; ParseReference lets its
+// methods also accept a digest directly.
+type Reference interface {
+	// String returns the original reference string.
+	String() string
+}
+
+// tagReference is a Reference that names a tag.
+type tagReference string
+
+func (r tagReference) String() string { return string(r) }
+
+// digestReference is a Reference that names a manifest by content digest.
+type digestReference digest.Digest
+
+func (r digestReference) String() string { return string(r) }
+
+// ParseReference classifies ref as a digest or a tag name. Any string that parses as a valid digest is
+// treated as a digest reference; everything else is treated as a tag name, since tag names and digests
+// never overlap syntactically.
+func ParseReference(ref string) Reference {
+	if dgst, err := digest.ParseDigest(ref); err == nil {
+		return digestReference(dgst)
+	}
+	return tagReference(ref)
+}
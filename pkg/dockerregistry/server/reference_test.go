@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/docker/distribution/digest"
+)
+
+func TestParseReferenceDigest(t *testing.T) {
+	const dgst = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+	ref := ParseReference(dgst)
+	d, ok := ref.(digestReference)
+	if !ok {
+		t.Fatalf("ParseReference(%q) = %T, want digestReference", dgst, ref)
+	}
+	if d.String() != dgst {
+		t.Errorf("String() = %q, want %q", d.String(), dgst)
+	}
+}
+
+func TestParseReferenceTag(t *testing.T) {
+	for _, tag := range []string{"latest", "v1.2.3", "sha256-not-quite-a-digest"} {
+		ref := ParseReference(tag)
+		tr, ok := ref.(tagReference)
+		if !ok {
+			t.Fatalf("ParseReference(%q) = %T, want tagReference", tag, ref)
+		}
+		if tr.String() != tag {
+			t.Errorf("String() = %q, want %q", tr.String(), tag)
+		}
+	}
+}
+
+func TestParseReferenceRoundTripsThroughDigestType(t *testing.T) {
+	const dgst = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+	ref, ok := ParseReference(dgst).(digestReference)
+	if !ok {
+		t.Fatalf("ParseReference(%q) did not return a digestReference", dgst)
+	}
+	if digest.Digest(ref) != digest.Digest(dgst) {
+		t.Errorf("digest.Digest(ref) = %q, want %q", digest.Digest(ref), dgst)
+	}
+}
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/context"
 	"github.com/docker/distribution/digest"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	imageapiv1 "github.com/openshift/api/image/v1"
@@ -18,18 +21,94 @@ type tagService struct {
 
 	imageStream        *imageStream
 	pullthroughEnabled bool
+
+	// strictTagsOnly disables digest-reference support in Get/Untag, restoring the historical behavior of
+	// treating every reference as a tag name. It exists as a compatibility escape hatch for callers that
+	// relied on that behavior.
+	strictTagsOnly bool
+
+	// eventSink receives events for tag creations and deletions. It may be nil; sink() then falls back to
+	// DefaultEventSink.
+	eventSink EventSink
 }
 
-func (t tagService) Get(ctx context.Context, tag string) (distribution.Descriptor, error) {
+// sink returns the EventSink tag mutations should be reported to, falling back to DefaultEventSink so
+// callers never need a nil check before emitting an event.
+func (t tagService) sink() EventSink {
+	if t.eventSink != nil {
+		return t.eventSink
+	}
+	return DefaultEventSink
+}
+
+// getImage resolves dgst to an Image, consulting imageCache first so that once a digest has been
+// resolved once, repeated Get/Lookup/Untag calls for it skip the Kubernetes API round trip.
+func (t tagService) getImage(ctx context.Context, dgst digest.Digest) (*imageapiv1.Image, error) {
+	if image, ok := imageCache.Get(dgst); ok {
+		return image, nil
+	}
+
+	image, err := t.imageStream.getImage(ctx, dgst)
+	if err != nil {
+		return nil, err
+	}
+
+	imageCache.Put(dgst, image)
+	return image, nil
+}
+
+// createTagEvent builds the Event reported for a tag create/delete against image, recomputing the
+// canonical digest and media type from the manifest itself rather than trusting the stored annotations,
+// mirroring how createManifestEvent recomputes its descriptor via UnmarshalManifest.
+func (t tagService) createTagEvent(action EventAction, tag string, image *imageapiv1.Image) Event {
+	event := Event{
+		Action:     action,
+		Repository: t.imageStream.Reference(),
+		Tag:        tag,
+		Digest:     image.Name,
+		MediaType:  image.DockerImageManifestMediaType,
+		Size:       int64(len(image.DockerImageManifest)),
+	}
+
+	manifest, err := NewManifestFromImage(image)
+	if err != nil {
+		return event
+	}
+
+	handler, err := NewManifestHandler("", nil, manifest)
+	if err != nil {
+		return event
+	}
+
+	mediaType, _, canonical, err := handler.Payload()
+	if err != nil {
+		return event
+	}
+	event.MediaType = mediaType
+	event.Digest = digest.FromBytes(canonical).String()
+	event.Size = int64(len(canonical))
+	return event
+}
+
+// Get resolves ref, which may be either a tag name or (unless strictTagsOnly is set) a digest, to a
+// descriptor. This mirrors the v2 manifest route, whose "reference" path segment already accepts
+// tag|digest.
+func (t tagService) Get(ctx context.Context, ref string) (distribution.Descriptor, error) {
+	if !t.strictTagsOnly {
+		if dgst, ok := ParseReference(ref).(digestReference); ok {
+			return t.getByDigest(ctx, digest.Digest(dgst))
+		}
+	}
+
 	imageStream, err := t.imageStream.imageStreamGetter.get()
 	if err != nil {
 		context.GetLogger(ctx).Errorf("error retrieving ImageStream %s: %v", t.imageStream.Reference(), err)
 		return distribution.Descriptor{}, distribution.ErrRepositoryUnknown{Name: t.imageStream.Reference()}
 	}
 
-	te := util.LatestTaggedImage(imageStream, tag)
+	te := util.LatestTaggedImage(imageStream, ref)
 	if te == nil {
-		return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: tag}
+		return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: ref}
 	}
 	dgst, err := digest.ParseDigest(te.Image)
 	if err != nil {
@@ -37,19 +116,69 @@ func (t tagService) Get(ctx context.Context, tag string) (distribution.Descripto
 	}
 
 	if !t.pullthroughEnabled {
-		image, err := t.imageStream.getImage(ctx, dgst)
+		image, err := t.getImage(ctx, dgst)
+		if err != nil {
+			return distribution.Descriptor{}, err
+		}
+
+		if !isImageManaged(image) {
+			return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: ref}
+		}
+
+		// Only emitted here, not for pullthrough repositories: pullthrough intentionally skips the image
+		// lookup above for every pull to avoid the extra round trip, and that lookup is what a manifest_pull
+		// event needs its media type and size from.
+		EmitManifestEvent(ctx, t.sink(), EventActionManifestPull, t.imageStream.Reference(), image.DockerImageManifestMediaType, dgst, int64(len(image.DockerImageManifest)))
+	}
+
+	return distribution.Descriptor{Digest: dgst}, nil
+}
+
+// getByDigest resolves a reference that is already known to be a digest, bypassing the tag history lookup
+// entirely. dgst must still belong to this image stream's tag history — otherwise any digest that is
+// "managed" anywhere in the cluster would be servable from every repository, leaking images across
+// repositories — and, like a tag-based Get, the image has to be verified as managed unless pullthrough is
+// enabled.
+func (t tagService) getByDigest(ctx context.Context, dgst digest.Digest) (distribution.Descriptor, error) {
+	imageStream, err := t.imageStream.imageStreamGetter.get()
+	if err != nil {
+		context.GetLogger(ctx).Errorf("error retrieving ImageStream %s: %v", t.imageStream.Reference(), err)
+		return distribution.Descriptor{}, distribution.ErrRepositoryUnknown{Name: t.imageStream.Reference()}
+	}
+
+	if !digestTagged(imageStream, dgst) {
+		return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: dgst.String()}
+	}
+
+	if !t.pullthroughEnabled {
+		image, err := t.getImage(ctx, dgst)
 		if err != nil {
 			return distribution.Descriptor{}, err
 		}
 
 		if !isImageManaged(image) {
-			return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: tag}
+			return distribution.Descriptor{}, distribution.ErrTagUnknown{Tag: dgst.String()}
 		}
+
+		EmitManifestEvent(ctx, t.sink(), EventActionManifestPull, t.imageStream.Reference(), image.DockerImageManifestMediaType, dgst, int64(len(image.DockerImageManifest)))
 	}
 
 	return distribution.Descriptor{Digest: dgst}, nil
 }
 
+// digestTagged reports whether dgst appears anywhere in the tag history of imageStream's status, including
+// entries that are no longer the latest image of their tag.
+func digestTagged(imageStream *imageapiv1.ImageStream, dgst digest.Digest) bool {
+	for _, history := range imageStream.Status.Tags {
+		for _, item := range history.Items {
+			if item.Image == dgst.String() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (t tagService) All(ctx context.Context) ([]string, error) {
 	tags := []string{}
 
@@ -80,7 +209,7 @@ func (t tagService) All(ctx context.Context) ([]string, error) {
 				continue
 			}
 
-			image, err := t.imageStream.getImage(ctx, dgst)
+			image, err := t.getImage(ctx, dgst)
 			if err != nil {
 				context.GetLogger(ctx).Errorf("unable to get image %s %s: %v", t.imageStream.Reference(), dgst.String(), err)
 				continue
@@ -132,7 +261,7 @@ func (t tagService) Lookup(ctx context.Context, desc distribution.Descriptor) ([
 
 		managed, found := managedImages[history.Items[0].Image]
 		if !found {
-			image, err := t.imageStream.getImage(ctx, dgst)
+			image, err := t.getImage(ctx, dgst)
 			if err != nil {
 				context.GetLogger(ctx).Errorf("unable to get image %s %s: %v", t.imageStream.Reference(), dgst.String(), err)
 				continue
@@ -151,6 +280,15 @@ func (t tagService) Lookup(ctx context.Context, desc distribution.Descriptor) ([
 	return tags, nil
 }
 
+// Tag records dgst under tag by creating an ImageStreamImport with Import: true and a From reference that
+// points back at the image's own DockerImageReference. This costs a synchronous round trip through the
+// import controller on every push, in exchange for getting quota enforcement and per-image
+// success/failure status for free from that controller rather than reimplementing both here against a
+// plain ImageStreamMapping write. Import: false does not create the tag at all when the referenced image
+// isn't already present in the stream's spec, so it isn't a drop-in replacement for this path. If the
+// added latency becomes a real problem, look at giving the import controller a fast path for references
+// that already resolve to an Image object in the same stream, rather than reverting to the pure
+// metadata write and losing the quota/status checks.
 func (t tagService) Tag(ctx context.Context, tag string, dgst distribution.Descriptor) error {
 	imageStream, err := t.imageStream.imageStreamGetter.get()
 	if err != nil {
@@ -169,25 +307,97 @@ func (t tagService) Tag(ctx context.Context, tag string, dgst distribution.Descr
 		return distribution.ErrRepositoryUnknown{Name: t.imageStream.Reference()}
 	}
 
-	ism := imageapiv1.ImageStreamMapping{
+	tagReference := findTagReference(imageStream, tag)
+
+	isi := &imageapiv1.ImageStreamImport{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: imageStream.Namespace,
 			Name:      imageStream.Name,
 		},
-		Tag:   tag,
-		Image: *image,
+		Spec: imageapiv1.ImageStreamImportSpec{
+			Import: true,
+			Images: []imageapiv1.ImageImportSpec{
+				{
+					From: corev1.ObjectReference{
+						Kind: "DockerImage",
+						Name: image.DockerImageReference,
+					},
+					To: &corev1.LocalObjectReference{
+						Name: tag,
+					},
+					ImportPolicy:    importPolicyFromTagReference(tagReference),
+					ReferencePolicy: referencePolicyFromTagReference(tagReference),
+				},
+			},
+		},
 	}
 
-	_, err = t.imageStream.registryOSClient.ImageStreamMappings(imageStream.Namespace).Create(&ism)
+	isi, err = t.imageStream.registryOSClient.ImageStreamImports(imageStream.Namespace).Create(isi)
 	if quotautil.IsErrorQuotaExceeded(err) {
-		context.GetLogger(ctx).Errorf("denied creating ImageStreamMapping: %v", err)
+		context.GetLogger(ctx).Errorf("denied creating ImageStreamImport: %v", err)
 		return distribution.ErrAccessDenied
 	}
+	if err != nil {
+		return err
+	}
+
+	// Create can return successfully at the transport level while the per-image import it carried still
+	// failed (quota, not found, forbidden, ...), exactly what `oc import-image` checks before declaring an
+	// import successful. Surface that failure instead of treating the tag as created.
+	if err := importedImageStatus(isi, tag); err != nil {
+		context.GetLogger(ctx).Errorf("%v", err)
+		return err
+	}
+
+	writeEvent(ctx, t.sink(), t.createTagEvent(EventActionTagCreate, tag, image))
+	EmitManifestEvent(ctx, t.sink(), EventActionManifestPush, t.imageStream.Reference(), image.DockerImageManifestMediaType, dgst.Digest, int64(len(image.DockerImageManifest)))
 
-	return err
+	return nil
 }
 
-func (t tagService) Untag(ctx context.Context, tag string) error {
+// Untag removes a tag, or (unless strictTagsOnly is set) every tag currently resolving to a given
+// digest.
+func (t tagService) Untag(ctx context.Context, ref string) error {
+	if !t.strictTagsOnly {
+		if dgst, ok := ParseReference(ref).(digestReference); ok {
+			return t.untagByDigest(ctx, digest.Digest(dgst))
+		}
+	}
+
+	return t.untagByName(ctx, ref)
+}
+
+// untagByDigest removes every tag in the image stream whose latest tagged image resolves to dgst.
+func (t tagService) untagByDigest(ctx context.Context, dgst digest.Digest) error {
+	imageStream, err := t.imageStream.imageStreamGetter.get()
+	if err != nil {
+		context.GetLogger(ctx).Errorf("error retrieving ImageStream %s: %v", t.imageStream.Reference(), err)
+		return distribution.ErrRepositoryUnknown{Name: t.imageStream.Reference()}
+	}
+
+	var tags []string
+	for _, history := range imageStream.Status.Tags {
+		if len(history.Items) == 0 {
+			continue
+		}
+		if history.Items[0].Image == dgst.String() {
+			tags = append(tags, history.Tag)
+		}
+	}
+
+	if len(tags) == 0 {
+		return distribution.ErrTagUnknown{Tag: dgst.String()}
+	}
+
+	for _, tag := range tags {
+		if err := t.untagByName(ctx, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t tagService) untagByName(ctx context.Context, tag string) error {
 	imageStream, err := t.imageStream.imageStreamGetter.get()
 	if err != nil {
 		context.GetLogger(ctx).Errorf("error retrieving ImageStream %s: %v", t.imageStream.Reference(), err)
@@ -199,21 +409,96 @@ func (t tagService) Untag(ctx context.Context, tag string) error {
 		return distribution.ErrTagUnknown{Tag: tag}
 	}
 
-	if !t.pullthroughEnabled {
-		dgst, err := digest.ParseDigest(te.Image)
-		if err != nil {
-			return err
-		}
+	dgst, err := digest.ParseDigest(te.Image)
+	if err != nil {
+		return err
+	}
 
-		image, err := t.imageStream.getImage(ctx, dgst)
-		if err != nil {
-			return err
+	image, err := t.getImage(ctx, dgst)
+	if err != nil {
+		return err
+	}
+
+	if !t.pullthroughEnabled && !isImageManaged(image) {
+		return distribution.ErrTagUnknown{Tag: tag}
+	}
+
+	if err := t.imageStream.registryOSClient.ImageStreamTags(imageStream.Namespace).Delete(imageapi.JoinImageStreamTag(imageStream.Name, tag), &metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+
+	writeEvent(ctx, t.sink(), t.createTagEvent(EventActionTagDelete, tag, image))
+
+	// The manifest and image caches are keyed by digest, not by tag, so only invalidate them -- and only
+	// report the manifest as deleted -- once we know no other tag in this image stream still points at the
+	// same digest. Two concurrent Untag calls racing on two different tags for the same digest can both
+	// observe digestStillTagged == false and both emit the event; the event system does not promise
+	// exactly-once delivery elsewhere either (webhookEventSink in events.go retries failed deliveries and
+	// drops a batch once it gives up).
+	if !t.digestStillTagged(ctx, imageStream, dgst) {
+		manifestCache.Delete(dgst)
+		imageCache.Delete(dgst)
+		EmitManifestEvent(ctx, t.sink(), EventActionManifestDelete, t.imageStream.Reference(), image.DockerImageManifestMediaType, dgst, int64(len(image.DockerImageManifest)))
+	}
+
+	return nil
+}
+
+// digestStillTagged reports whether dgst still appears in the image stream's tag history (see
+// digestTagged). It re-fetches the
+// image stream since the Delete call above may have raced with another update to it.
+func (t tagService) digestStillTagged(ctx context.Context, imageStream *imageapiv1.ImageStream, dgst digest.Digest) bool {
+	current, err := t.imageStream.imageStreamGetter.get()
+	if err != nil {
+		context.GetLogger(ctx).Errorf("error retrieving ImageStream %s: %v", t.imageStream.Reference(), err)
+		return true
+	}
+
+	return digestTagged(current, dgst)
+}
+
+// importedImageStatus translates the per-image status an ImageStreamImport reports for tag into the error
+// tagService.Tag should return, or nil if the import actually succeeded. It exists as its own function,
+// independent of any live ImageStreamImport Create call, so the status/reason-translation logic can be
+// tested directly against a fabricated ImageStreamImportStatus.
+func importedImageStatus(isi *imageapiv1.ImageStreamImport, tag string) error {
+	if len(isi.Status.Images) != 1 {
+		return fmt.Errorf("unexpected number of image statuses returned for import of %s: %d", tag, len(isi.Status.Images))
+	}
+	if imageStatus := isi.Status.Images[0].Status; imageStatus.Status != metav1.StatusSuccess {
+		if imageStatus.Reason == metav1.StatusReasonForbidden {
+			return distribution.ErrAccessDenied
 		}
+		return fmt.Errorf("import of %s failed: %s: %s", tag, imageStatus.Reason, imageStatus.Message)
+	}
+	return nil
+}
 
-		if !isImageManaged(image) {
-			return distribution.ErrTagUnknown{Tag: tag}
+// findTagReference returns the existing spec for tag in imageStream, or nil if the tag has no spec of its
+// own yet (e.g. it is being created for the first time by this push).
+func findTagReference(imageStream *imageapiv1.ImageStream, tag string) *imageapiv1.TagReference {
+	for i := range imageStream.Spec.Tags {
+		if imageStream.Spec.Tags[i].Name == tag {
+			return &imageStream.Spec.Tags[i]
 		}
 	}
+	return nil
+}
+
+// importPolicyFromTagReference carries a tag's existing import policy into the ImageStreamImport used to
+// create it, so a push produces a tag consistent with what `oc import-image` would have produced.
+func importPolicyFromTagReference(ref *imageapiv1.TagReference) imageapiv1.TagImportPolicy {
+	if ref == nil {
+		return imageapiv1.TagImportPolicy{}
+	}
+	return ref.ImportPolicy
+}
 
-	return t.imageStream.registryOSClient.ImageStreamTags(imageStream.Namespace).Delete(imageapi.JoinImageStreamTag(imageStream.Name, tag), &metav1.DeleteOptions{})
+// referencePolicyFromTagReference carries a tag's existing reference policy into the ImageStreamImport
+// used to create it.
+func referencePolicyFromTagReference(ref *imageapiv1.TagReference) imageapiv1.TagReferencePolicy {
+	if ref == nil {
+		return imageapiv1.TagReferencePolicy{}
+	}
+	return ref.ReferencePolicy
 }
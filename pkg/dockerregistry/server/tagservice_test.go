@@ -0,0 +1,97 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+
+	imageapiv1 "github.com/openshift/api/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const tagServiceTestDigest = "sha256:7aa305ea22da1fba563e61bd8da7ef5a176db3979ce50c8d4bac368ccc9c874a"
+
+func TestDigestTaggedFound(t *testing.T) {
+	imageStream := &imageapiv1.ImageStream{
+		Status: imageapiv1.ImageStreamStatus{
+			Tags: []imageapiv1.NamedTagEventList{
+				{
+					Tag:   "latest",
+					Items: []imageapiv1.TagEvent{{Image: tagServiceTestDigest}},
+				},
+			},
+		},
+	}
+
+	if !digestTagged(imageStream, digest.Digest(tagServiceTestDigest)) {
+		t.Errorf("expected digestTagged to find %s in the tag history", tagServiceTestDigest)
+	}
+}
+
+func TestDigestTaggedNotFound(t *testing.T) {
+	imageStream := &imageapiv1.ImageStream{
+		Status: imageapiv1.ImageStreamStatus{
+			Tags: []imageapiv1.NamedTagEventList{
+				{
+					Tag:   "latest",
+					Items: []imageapiv1.TagEvent{{Image: "sha256:0000000000000000000000000000000000000000000000000000000000000000"}},
+				},
+			},
+		},
+	}
+
+	if digestTagged(imageStream, digest.Digest(tagServiceTestDigest)) {
+		t.Errorf("expected digestTagged to report false for a digest no tag resolves to")
+	}
+}
+
+func TestDigestTaggedEmptyHistory(t *testing.T) {
+	imageStream := &imageapiv1.ImageStream{}
+
+	if digestTagged(imageStream, digest.Digest(tagServiceTestDigest)) {
+		t.Errorf("expected digestTagged to report false for an image stream with no tag history")
+	}
+}
+
+func newImportStatus(status metav1.Status) *imageapiv1.ImageStreamImport {
+	return &imageapiv1.ImageStreamImport{
+		Status: imageapiv1.ImageStreamImportStatus{
+			Images: []imageapiv1.ImageImportStatus{{Status: status}},
+		},
+	}
+}
+
+func TestImportedImageStatusSuccess(t *testing.T) {
+	isi := newImportStatus(metav1.Status{Status: metav1.StatusSuccess})
+
+	if err := importedImageStatus(isi, "latest"); err != nil {
+		t.Errorf("importedImageStatus: %v", err)
+	}
+}
+
+func TestImportedImageStatusForbidden(t *testing.T) {
+	isi := newImportStatus(metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonForbidden, Message: "quota exceeded"})
+
+	err := importedImageStatus(isi, "latest")
+	if err != distribution.ErrAccessDenied {
+		t.Errorf("importedImageStatus = %v, want %v", err, distribution.ErrAccessDenied)
+	}
+}
+
+func TestImportedImageStatusOtherFailure(t *testing.T) {
+	isi := newImportStatus(metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Message: "image not found"})
+
+	err := importedImageStatus(isi, "latest")
+	if err == nil || err == distribution.ErrAccessDenied {
+		t.Errorf("importedImageStatus = %v, want a non-nil, non-ErrAccessDenied error", err)
+	}
+}
+
+func TestImportedImageStatusUnexpectedImageCount(t *testing.T) {
+	isi := &imageapiv1.ImageStreamImport{}
+
+	if err := importedImageStatus(isi, "latest"); err == nil {
+		t.Errorf("expected an error when the import reports zero image statuses")
+	}
+}